@@ -0,0 +1,174 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/sprig/v3"
+	crdsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// certManagerCRDName is installed by every supported cert-manager release and
+// is used purely as a detection probe, the same trick KUDO uses before it
+// starts talking to cert-manager.
+const certManagerCRDName = "certificates.cert-manager.io"
+
+// certManagerAPIVersions are the cert-manager API versions we know how to
+// drive, most preferred first.
+var certManagerAPIVersions = []string{"v1", "v1beta1", "v1alpha2"}
+
+// certManagerEnabledData is implemented by InitData when the Karmada CR has
+// opted into cert-manager managing the CRD conversion webhook CA. It's
+// checked with a type assertion rather than added to the InitData interface
+// directly, so this package doesn't need to know the exact shape of the
+// Karmada CR spec.
+type certManagerEnabledData interface {
+	CertManagerEnabled() bool
+}
+
+// isCertManagerEnabled reports whether the Karmada CR backing data opted into
+// cert-manager mode for CRD conversion webhooks.
+func isCertManagerEnabled(data InitData) bool {
+	aware, ok := data.(certManagerEnabledData)
+	return ok && aware.CertManagerEnabled()
+}
+
+// certManagerIssuerRefData is implemented by InitData when the Karmada CR
+// names the cert-manager Issuer/ClusterIssuer that should sign the CRD
+// conversion webhook Certificate. It's checked with a type assertion for the
+// same reason as certManagerEnabledData. Without it there is no issuer for
+// cert-manager to hand the Certificate to, so it would never become Ready.
+type certManagerIssuerRefData interface {
+	CertManagerIssuerRef() (name, kind string)
+}
+
+// certManagerIssuerRef returns the issuerRef the CRD conversion webhook
+// Certificate should reference, as configured on the Karmada CR. The caller
+// is responsible for failing loudly if name is empty, since the operator has
+// no way to know whether a cluster admin's Issuer or ClusterIssuer is ready.
+func certManagerIssuerRef(data InitData) (name, kind string) {
+	aware, ok := data.(certManagerIssuerRefData)
+	if !ok {
+		return "", ""
+	}
+	return aware.CertManagerIssuerRef()
+}
+
+// detectCertManagerAPIVersion looks up the cert-manager CRD and returns the
+// newest API version it serves.
+func detectCertManagerAPIVersion(crdsClient *crdsclient.Clientset) (string, error) {
+	crd, err := crdsClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), certManagerCRDName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("cert-manager mode requested but CRD %s was not found in the cluster", certManagerCRDName)
+		}
+		return "", fmt.Errorf("failed to look up cert-manager CRD %s, err: %w", certManagerCRDName, err)
+	}
+
+	served := make(map[string]bool, len(crd.Spec.Versions))
+	for _, v := range crd.Spec.Versions {
+		served[v.Name] = v.Served
+	}
+
+	for _, version := range certManagerAPIVersions {
+		if served[version] {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("cert-manager is installed but serves none of the supported API versions %v", certManagerAPIVersions)
+}
+
+var certificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Resource: "certificates"}
+
+// ensureConversionCertificate makes sure a cert-manager Certificate exists for
+// the CRD conversion webhook serving cert, so cert-manager rotates the CA
+// instead of the operator managing it by hand. issuerName/issuerKind must
+// name an Issuer or ClusterIssuer the cluster admin already created; without
+// one cert-manager has nothing to hand the Certificate to and it never
+// becomes Ready.
+func ensureConversionCertificate(config *rest.Config, apiVersion, namespace, name, secretName, serviceName, issuerName, issuerKind string) error {
+	if issuerName == "" {
+		return fmt.Errorf("cert-manager mode requires spec.certManager.issuerRef.name to be set to an existing Issuer or ClusterIssuer")
+	}
+	if issuerKind == "" {
+		issuerKind = "Issuer"
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to build dynamic client for cert-manager Certificate, err: %w", err)
+	}
+
+	cert := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cert-manager.io/" + apiVersion,
+			"kind":       "Certificate",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"secretName": secretName,
+				"dnsNames": []interface{}{
+					serviceName,
+					fmt.Sprintf("%s.%s", serviceName, namespace),
+					fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+				},
+				"issuerRef": map[string]interface{}{
+					"name": issuerName,
+					"kind": issuerKind,
+				},
+			},
+		},
+	}
+
+	_, err = dynamicClient.Resource(certificateGVR).Namespace(namespace).Create(context.TODO(), cert, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to create cert-manager Certificate %s/%s, err: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// certManagerInjectCAAnnotation returns the cert-manager CA-injector
+// annotation that lets cert-manager keep a CRD's conversion webhook caBundle
+// in sync with the named Certificate's issued secret.
+func certManagerInjectCAAnnotation(namespace, certificateName string) map[string]string {
+	return map[string]string{
+		"cert-manager.io/inject-ca-from": fmt.Sprintf("%s/%s", namespace, certificateName),
+	}
+}
+
+// allowedTemplateFuncs is the subset of sprig.TxtFuncMap() exposed to CRD
+// patch templates. Patch YAMLs can come from a remote CRDSource (an HTTPS
+// tarball or OCI artifact), so the full sprig set is deliberately not
+// exposed: it includes env/expandenv, which would let a malicious patch
+// template exfiltrate the operator pod's environment into a CRD field
+// visible to anyone with CRD read access. Only the string/conversion
+// helpers patch authors actually need for templating are allowed through.
+var allowedTemplateFuncs = []string{
+	"lower", "upper", "title", "trim", "trimAll", "trimPrefix", "trimSuffix",
+	"replace", "quote", "squote", "indent", "nindent", "toString", "toJson",
+	"b64enc", "b64dec", "default", "join", "split",
+}
+
+// templateFuncMap returns the sprig-style helper set exposed to CRD patch
+// templates, on top of the values in crdPatchTemplateData.
+func templateFuncMap() map[string]interface{} {
+	full := sprig.TxtFuncMap()
+	allowed := make(map[string]interface{}, len(allowedTemplateFuncs))
+	for _, name := range allowedTemplateFuncs {
+		if fn, ok := full[name]; ok {
+			allowed[name] = fn
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/karmada-io/karmada/operator/pkg/util/apiclient"
+)
+
+func TestRenderCrdPatchSubstitutesTemplateData(t *testing.T) {
+	patchPath := filepath.Join(t.TempDir(), "patch_widget0.yaml")
+	if err := os.WriteFile(patchPath, []byte(`spec:
+  conversion:
+    webhook:
+      clientConfig:
+        caBundle: {{ .CABundle }}
+        service:
+          name: {{ .ServiceName }}
+          namespace: {{ .Namespace }}
+          path: {{ .ConversionWebhookPath | upper }}
+`), 0644); err != nil {
+		t.Fatalf("failed to write patch fixture: %v", err)
+	}
+
+	rendered, err := renderCrdPatch(patchPath, crdPatchTemplateData{
+		Namespace:             "karmada-system",
+		ServiceName:           "karmada-webhook",
+		CABundle:              "ZmFrZQ==",
+		ConversionWebhookPath: "/convert/widget0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error rendering patch: %v", err)
+	}
+
+	out := string(rendered)
+	for _, want := range []string{"caBundle: ZmFrZQ==", "name: karmada-webhook", "namespace: karmada-system", "path: /CONVERT/WIDGET0"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected rendered patch to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderCrdPatchRejectsDisallowedFuncs(t *testing.T) {
+	patchPath := filepath.Join(t.TempDir(), "patch_widget0.yaml")
+	if err := os.WriteFile(patchPath, []byte(`spec: {{ env "HOME" }}`), 0644); err != nil {
+		t.Fatalf("failed to write patch fixture: %v", err)
+	}
+
+	if _, err := renderCrdPatch(patchPath, crdPatchTemplateData{}); err == nil {
+		t.Fatal("expected rendering a template calling env() to fail, since it's not in the allowed func map")
+	}
+}
+
+func TestDetectCertManagerAPIVersionPrefersNewest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crd := map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   map[string]interface{}{"name": certManagerCRDName},
+			"spec": map[string]interface{}{
+				"group": "cert-manager.io",
+				"versions": []interface{}{
+					map[string]interface{}{"name": "v1beta1", "served": true},
+					map[string]interface{}{"name": "v1", "served": true},
+					map[string]interface{}{"name": "v1alpha2", "served": false},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		out, _ := json.Marshal(crd)
+		_, _ = w.Write(out)
+	}))
+	defer server.Close()
+
+	crdsClient, err := apiclient.NewCRDsClient(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to build crds client: %v", err)
+	}
+
+	version, err := detectCertManagerAPIVersion(crdsClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != "v1" {
+		t.Fatalf("expected v1 to be preferred over v1beta1, got %q", version)
+	}
+}
+
+func TestDetectCertManagerAPIVersionNotInstalled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"kind":"Status","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+
+	crdsClient, err := apiclient.NewCRDsClient(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to build crds client: %v", err)
+	}
+
+	if _, err := detectCertManagerAPIVersion(crdsClient); err == nil {
+		t.Fatal("expected an error when the cert-manager CRD isn't installed")
+	}
+}
@@ -0,0 +1,96 @@
+package tasks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/karmada-io/karmada/operator/pkg/workflow"
+)
+
+// defaultCRDApplyConcurrency bounds how many CRDs are created/patched in
+// parallel when InitData doesn't configure its own value.
+const defaultCRDApplyConcurrency = 8
+
+// crdConcurrencyAwareData is implemented by InitData when the Karmada CR
+// overrides how many CRDs are applied or patched concurrently. Checked via a
+// type assertion, defaulting to defaultCRDApplyConcurrency.
+type crdConcurrencyAwareData interface {
+	CRDApplyConcurrency() int
+}
+
+func crdApplyConcurrency(data InitData) int {
+	if aware, ok := data.(crdConcurrencyAwareData); ok {
+		if n := aware.CRDApplyConcurrency(); n > 0 {
+			return n
+		}
+	}
+	return defaultCRDApplyConcurrency
+}
+
+// progressReporterAwareData is implemented by InitData when the caller wants
+// progress events published as createCrds/patchCrds process each CRD.
+type progressReporterAwareData interface {
+	ProgressReporter() workflow.ProgressReporter
+}
+
+func progressReporterFor(data InitData) workflow.ProgressReporter {
+	if aware, ok := data.(progressReporterAwareData); ok {
+		if reporter := aware.ProgressReporter(); reporter != nil {
+			return reporter
+		}
+	}
+	return workflow.NoopProgressReporter{}
+}
+
+// filterSlice returns the items of in for which keep reports true.
+func filterSlice[T any](in []T, keep func(T) bool) []T {
+	out := make([]T, 0, len(in))
+	for _, item := range in {
+		if keep(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// forEachConcurrent runs fn for every item in items using up to concurrency
+// goroutines at a time, reports progress as taskName to reporter after each
+// item completes, and joins every error fn returns into a single error via
+// errors.Join. A nil error means every item succeeded.
+func forEachConcurrent[T any](items []T, concurrency int, taskName string, reporter workflow.ProgressReporter, fn func(T) error) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		errs      []error
+		completed int
+	)
+
+	total := len(items)
+	sem := make(chan struct{}, concurrency)
+	for _, item := range items {
+		item := item
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := fn(item)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			completed++
+			reporter.Report(taskName, completed, total)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
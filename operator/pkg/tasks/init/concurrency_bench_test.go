@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"github.com/karmada-io/karmada/operator/pkg/util/apiclient"
+	"github.com/karmada-io/karmada/operator/pkg/workflow"
+)
+
+// shippedCRDCount approximates the number of CRDs karmada ships today, so the
+// benchmark reflects a realistic fresh-install fan-out.
+const shippedCRDCount = 30
+
+// writeBenchCRDFixtures writes shippedCRDCount minimal, valid
+// CustomResourceDefinition YAMLs into dir, one per file, so createCrds has
+// something real to read and server-side apply.
+func writeBenchCRDFixtures(b *testing.B, dir string) {
+	b.Helper()
+	for i := 0; i < shippedCRDCount; i++ {
+		name := fmt.Sprintf("widget%d.work.karmada.io", i)
+		doc := fmt.Sprintf(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: %s
+spec:
+  group: work.karmada.io
+  names:
+    kind: Widget%d
+    plural: widget%ds
+    singular: widget%d
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+`, name, i, i, i)
+		if err := os.WriteFile(path.Join(dir, fmt.Sprintf("widget%d.yaml", i)), []byte(doc), 0644); err != nil {
+			b.Fatalf("failed to write crd fixture: %v", err)
+		}
+	}
+}
+
+// writeBenchPatchFixtures writes shippedCRDCount CRD conversion-webhook patch
+// templates into dir, named so splitToCrdNameFormFile resolves them back to
+// the "widgetN.work.karmada.io" CRDs writeBenchCRDFixtures created.
+func writeBenchPatchFixtures(b *testing.B, dir string) {
+	b.Helper()
+	for i := 0; i < shippedCRDCount; i++ {
+		doc := `spec:
+  conversion:
+    strategy: Webhook
+    webhook:
+      clientConfig:
+        caBundle: {{ .CABundle }}
+        service:
+          name: {{ .ServiceName }}
+          namespace: {{ .Namespace }}
+          path: {{ .ConversionWebhookPath }}
+      conversionReviewVersions: ["v1"]
+`
+		if err := os.WriteFile(path.Join(dir, fmt.Sprintf("patch_widget%d.yaml", i)), []byte(doc), 0644); err != nil {
+			b.Fatalf("failed to write crd patch fixture: %v", err)
+		}
+	}
+}
+
+// newBenchCRDsServer starts a fake apiserver that accepts any
+// CustomResourceDefinition PATCH (server-side apply or JSON patch) and echoes
+// the request back, so ApplyCustomResourceDefinition/PatchCustomResourceDefinition
+// round-trip like they would against a real cluster.
+func newBenchCRDsServer(b *testing.B) *httptest.Server {
+	b.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPatch {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		crd := map[string]interface{}{}
+		if len(body) > 0 && json.Valid(body) {
+			_ = json.Unmarshal(body, &crd)
+		}
+		crd["apiVersion"] = "apiextensions.k8s.io/v1"
+		crd["kind"] = "CustomResourceDefinition"
+		out, _ := json.Marshal(crd)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(out)
+	}))
+	b.Cleanup(server.Close)
+	return server
+}
+
+// benchmarkCRDApply exercises the real createCrds and patchCrds code paths
+// (fixture reading, JSON decode/template render, server-side apply/patch,
+// and the worker pool) over the ~30 CRDs karmada ships today, against a fake
+// apiserver instead of time.Sleep, so a regression in the actual apply/patch
+// logic shows up here.
+func benchmarkCRDApply(b *testing.B, concurrency int) {
+	crdsDir, patchDir := b.TempDir(), b.TempDir()
+	writeBenchCRDFixtures(b, crdsDir)
+	writeBenchPatchFixtures(b, patchDir)
+
+	server := newBenchCRDsServer(b)
+	crdsClient, err := apiclient.NewCRDsClient(&rest.Config{Host: server.URL})
+	if err != nil {
+		b.Fatalf("failed to build crds client: %v", err)
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	kubeClient := kubefake.NewSimpleClientset()
+
+	templateData := crdPatchTemplateData{
+		Namespace:   "karmada-system",
+		ServiceName: "karmada-webhook",
+		CABundle:    "ZmFrZS1jYS1idW5kbGU=",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		karmadaVersion := fmt.Sprintf("v1.9.%d", i)
+		if err := createCrds(crdsClient, dynamicClient, kubeClient, "karmada-system", karmadaVersion, crdsDir, false, concurrency, workflow.NoopProgressReporter{}); err != nil {
+			b.Fatalf("unexpected error in createCrds: %v", err)
+		}
+		templateData.KarmadaVersion = karmadaVersion
+		if err := patchCrds(crdsClient, patchDir, templateData, concurrency, workflow.NoopProgressReporter{}); err != nil {
+			b.Fatalf("unexpected error in patchCrds: %v", err)
+		}
+	}
+}
+
+// BenchmarkCRDApplySerial simulates the pre-worker-pool behavior of
+// createCrds/patchCrds: one CRD round-trip after another.
+func BenchmarkCRDApplySerial(b *testing.B) {
+	benchmarkCRDApply(b, 1)
+}
+
+// BenchmarkCRDApplyConcurrent simulates createCrds/patchCrds with the
+// default worker pool size, applying/patching CRDs defaultCRDApplyConcurrency
+// at a time instead of one at a time.
+func BenchmarkCRDApplyConcurrent(b *testing.B) {
+	benchmarkCRDApply(b, defaultCRDApplyConcurrency)
+}
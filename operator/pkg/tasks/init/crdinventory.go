@@ -0,0 +1,88 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// crdInventoryConfigMapName records, per karmada version, the set of CRD
+// names the operator last applied for that version. runCrds consults it to
+// tell a CRD the current version no longer ships from one left over from an
+// earlier install.
+const crdInventoryConfigMapName = "karmada-crds-inventory"
+
+// crdInventoryDataKey is the ConfigMap data key the JSON-encoded crdInventory is stored under.
+const crdInventoryDataKey = "inventory"
+
+// crdInventory maps a karmada version to the sorted CRD names applied for it.
+type crdInventory map[string][]string
+
+// crdInventorySnapshot is a crdInventory together with the ResourceVersion of
+// the ConfigMap it was read from, so saveCrdInventory can update the same
+// object instead of the apiserver rejecting a resourceVersion-less Update.
+// resourceVersion is empty when the ConfigMap doesn't exist yet.
+type crdInventorySnapshot struct {
+	inventory       crdInventory
+	resourceVersion string
+}
+
+// getCrdInventory reads the current crd inventory, returning an empty one if
+// it hasn't been recorded yet.
+func getCrdInventory(kubeClient kubernetes.Interface, namespace string) (crdInventorySnapshot, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), crdInventoryConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return crdInventorySnapshot{inventory: crdInventory{}}, nil
+	}
+	if err != nil {
+		return crdInventorySnapshot{}, fmt.Errorf("failed to get crd inventory configmap %s/%s, err: %w", namespace, crdInventoryConfigMapName, err)
+	}
+
+	inventory := crdInventory{}
+	if raw, ok := cm.Data[crdInventoryDataKey]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &inventory); err != nil {
+			return crdInventorySnapshot{}, fmt.Errorf("failed to decode crd inventory configmap %s/%s, err: %w", namespace, crdInventoryConfigMapName, err)
+		}
+	}
+	return crdInventorySnapshot{inventory: inventory, resourceVersion: cm.ResourceVersion}, nil
+}
+
+// saveCrdInventory records crdNames as the applied set for karmadaVersion,
+// leaving every other version's entry in snapshot.inventory untouched.
+func saveCrdInventory(kubeClient kubernetes.Interface, namespace, karmadaVersion string, crdNames []string, snapshot crdInventorySnapshot) error {
+	sorted := append([]string(nil), crdNames...)
+	sort.Strings(sorted)
+	snapshot.inventory[karmadaVersion] = sorted
+
+	raw, err := json.Marshal(snapshot.inventory)
+	if err != nil {
+		return fmt.Errorf("failed to encode crd inventory, err: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            crdInventoryConfigMapName,
+			Namespace:       namespace,
+			ResourceVersion: snapshot.resourceVersion,
+		},
+		Data: map[string]string{crdInventoryDataKey: string(raw)},
+	}
+
+	if snapshot.resourceVersion == "" {
+		if _, err := kubeClient.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create crd inventory configmap %s/%s, err: %w", namespace, crdInventoryConfigMapName, err)
+		}
+		return nil
+	}
+
+	if _, err := kubeClient.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update crd inventory configmap %s/%s, err: %w", namespace, crdInventoryConfigMapName, err)
+	}
+	return nil
+}
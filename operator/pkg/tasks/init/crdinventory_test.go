@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCrdInventoryRoundTrip(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	snapshot, err := getCrdInventory(client, "karmada-system")
+	if err != nil {
+		t.Fatalf("getCrdInventory on a missing configmap returned an error: %v", err)
+	}
+	if snapshot.resourceVersion != "" {
+		t.Fatalf("expected empty resourceVersion before the configmap is created, got %q", snapshot.resourceVersion)
+	}
+
+	if err := saveCrdInventory(client, "karmada-system", "v1.9.0", []string{"b", "a"}, snapshot); err != nil {
+		t.Fatalf("saveCrdInventory create failed: %v", err)
+	}
+
+	snapshot, err = getCrdInventory(client, "karmada-system")
+	if err != nil {
+		t.Fatalf("getCrdInventory after create returned an error: %v", err)
+	}
+	if snapshot.resourceVersion == "" {
+		t.Fatal("expected a non-empty resourceVersion after the configmap was created")
+	}
+	if got := snapshot.inventory["v1.9.0"]; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("expected sorted [a b], got %v", got)
+	}
+
+	// Updating after a prior save must succeed: this is the path that broke
+	// when saveCrdInventory built a bare ConfigMap{} with no ResourceVersion.
+	if err := saveCrdInventory(client, "karmada-system", "v1.10.0", []string{"c"}, snapshot); err != nil {
+		t.Fatalf("saveCrdInventory update failed: %v", err)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("karmada-system").Get(context.TODO(), crdInventoryConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to fetch configmap after update: %v", err)
+	}
+	if cm.ResourceVersion == snapshot.resourceVersion {
+		t.Fatal("expected the update to bump the configmap's resourceVersion")
+	}
+
+	final, err := getCrdInventory(client, "karmada-system")
+	if err != nil {
+		t.Fatalf("getCrdInventory after update returned an error: %v", err)
+	}
+	if len(final.inventory) != 2 {
+		t.Fatalf("expected both versions to be retained, got %v", final.inventory)
+	}
+}
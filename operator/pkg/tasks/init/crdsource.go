@@ -0,0 +1,309 @@
+package tasks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// CRDSourceType selects the implementation CRDSource resolves CRD manifests
+// from, set via the Karmada CR's spec.crds.source.
+type CRDSourceType string
+
+const (
+	// CRDSourceLocal reads CRD manifests from the operator pod's dataDir,
+	// the original and still-default behavior.
+	CRDSourceLocal CRDSourceType = "Local"
+	// CRDSourceHTTP downloads a CRD bundle tarball over HTTP(S).
+	CRDSourceHTTP CRDSourceType = "HTTP"
+	// CRDSourceOCI pulls a CRD bundle from an OCI registry.
+	CRDSourceOCI CRDSourceType = "OCI"
+)
+
+// CRDSourceConfig configures where CRDSource fetches CRD manifests from.
+type CRDSourceConfig struct {
+	Type CRDSourceType
+
+	// DataDir is used when Type is CRDSourceLocal, defaulting to
+	// InitData.DataDir() when unset.
+	DataDir string
+	// URL is the HTTP(S) base URL used when Type is CRDSourceHTTP; the
+	// bundle for a karmada version is fetched from {URL}/{karmadaVersion}.tar.gz.
+	URL string
+	// Checksum is the expected sha256 checksum (hex-encoded) of the tarball
+	// fetched from URL. Verification is skipped when it is empty.
+	Checksum string
+	// OCIReference is the OCI artifact reference used when Type is
+	// CRDSourceOCI, e.g. registry.example.com/karmada/crds. Pin it to a
+	// digest (registry.example.com/karmada/crds@sha256:...) to have oras-go
+	// verify the pulled manifest against that exact content digest; left
+	// unpinned, the karmadaVersion tag is pulled as-is and integrity relies
+	// entirely on registry/transport trust, the same as an unverified HTTP
+	// fetch with no Checksum configured.
+	OCIReference string
+	// CacheDir is where downloaded bundles are extracted to, for the HTTP
+	// and OCI source types.
+	CacheDir string
+}
+
+// crdSourceAwareData is implemented by InitData when the Karmada CR
+// configures a non-default CRD source. Checked via a type assertion so this
+// package doesn't need to know the exact shape of the Karmada CR spec.
+type crdSourceAwareData interface {
+	CRDSourceConfig() CRDSourceConfig
+}
+
+// CRDSource makes a karmada version's CRD bases and patches manifests
+// available on local disk and reports where to read them from.
+type CRDSource interface {
+	// Fetch returns the local directories holding the CRD bases and patches
+	// for karmadaVersion, downloading/extracting them first if needed.
+	Fetch(ctx context.Context, karmadaVersion string) (basesDir, patchesDir string, err error)
+}
+
+// resolveCRDSource builds the CRDSource configured for data, defaulting to
+// the on-disk dataDir behavior when the Karmada CR doesn't opt into another
+// source.
+func resolveCRDSource(data InitData) (CRDSource, error) {
+	config := CRDSourceConfig{Type: CRDSourceLocal, DataDir: data.DataDir()}
+	if aware, ok := data.(crdSourceAwareData); ok {
+		if c := aware.CRDSourceConfig(); c.Type != "" {
+			config = c
+		}
+	}
+	if config.DataDir == "" {
+		config.DataDir = data.DataDir()
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = path.Join(data.DataDir(), "crds-cache")
+	}
+
+	switch config.Type {
+	case CRDSourceLocal, "":
+		return &localCRDSource{dataDir: config.DataDir}, nil
+	case CRDSourceHTTP:
+		if config.URL == "" {
+			return nil, fmt.Errorf("crds source is HTTP but no URL was configured")
+		}
+		return &httpCRDSource{url: config.URL, checksum: config.Checksum, cacheDir: config.CacheDir}, nil
+	case CRDSourceOCI:
+		if config.OCIReference == "" {
+			return nil, fmt.Errorf("crds source is OCI but no reference was configured")
+		}
+		return &ociCRDSource{reference: config.OCIReference, cacheDir: config.CacheDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown crds source type %q", config.Type)
+	}
+}
+
+// localCRDSource reads CRDs from {dataDir}/{version}/crds/{bases,patches},
+// preserving the original behavior of runCrds.
+type localCRDSource struct {
+	dataDir string
+}
+
+func (s *localCRDSource) Fetch(_ context.Context, karmadaVersion string) (string, string, error) {
+	versionDir := path.Join(s.dataDir, karmadaVersion)
+	return path.Join(versionDir, "crds/bases"), path.Join(versionDir, "crds/patches"), nil
+}
+
+// httpCRDSource downloads a `{karmadaVersion}.tar.gz` CRD bundle from a
+// base URL, verifies its checksum, and extracts it into cacheDir.
+type httpCRDSource struct {
+	url      string
+	checksum string
+	cacheDir string
+}
+
+func (s *httpCRDSource) Fetch(ctx context.Context, karmadaVersion string) (string, string, error) {
+	destDir := path.Join(s.cacheDir, karmadaVersion)
+	basesDir, patchesDir := path.Join(destDir, "crds/bases"), path.Join(destDir, "crds/patches")
+	if _, err := os.Stat(destDir); err == nil {
+		return basesDir, patchesDir, nil
+	}
+
+	bundleURL := fmt.Sprintf("%s/%s.tar.gz", s.url, karmadaVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bundleURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build request for %s, err: %w", bundleURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download crd bundle from %s, err: %w", bundleURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download crd bundle from %s, got status %s", bundleURL, resp.Status)
+	}
+
+	if err := os.MkdirAll(s.cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create crd bundle cache dir %s, err: %w", s.cacheDir, err)
+	}
+	// Extract into a scratch directory first and only rename it into destDir
+	// once the checksum has been verified, so a checksum mismatch (or a
+	// crash mid-extraction) never leaves unverified content for the
+	// destDir-exists short-circuit above to pick up on the next call.
+	tmpDir, err := os.MkdirTemp(s.cacheDir, karmadaVersion+"-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create crd bundle scratch dir under %s, err: %w", s.cacheDir, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sum := sha256.New()
+	body := io.TeeReader(resp.Body, sum)
+	if err := extractTarGz(body, tmpDir); err != nil {
+		return "", "", fmt.Errorf("failed to extract crd bundle from %s, err: %w", bundleURL, err)
+	}
+	// extractTarGz stops at the tar end-of-archive marker, so hash whatever of
+	// the download it left unread (padding, gzip trailer) as well.
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return "", "", fmt.Errorf("failed to read crd bundle from %s, err: %w", bundleURL, err)
+	}
+
+	if s.checksum != "" {
+		if got := hex.EncodeToString(sum.Sum(nil)); got != s.checksum {
+			return "", "", fmt.Errorf("checksum mismatch for crd bundle %s: want %s, got %s", bundleURL, s.checksum, got)
+		}
+	}
+
+	if err := os.Rename(tmpDir, destDir); err != nil {
+		return "", "", fmt.Errorf("failed to publish crd bundle to %s, err: %w", destDir, err)
+	}
+
+	klog.V(2).InfoS("[crdSource] Fetched karmada crd bundle over HTTP", "url", bundleURL, "dest", destDir)
+	return basesDir, patchesDir, nil
+}
+
+// ociCRDSource pulls a CRD bundle published as an OCI artifact and extracts
+// it into cacheDir, letting air-gapped/GitOps users ship a signed CRD bundle
+// per karmada version and upgrade by bumping an image digest.
+type ociCRDSource struct {
+	reference string
+	cacheDir  string
+}
+
+func (s *ociCRDSource) Fetch(ctx context.Context, karmadaVersion string) (string, string, error) {
+	destDir := path.Join(s.cacheDir, karmadaVersion)
+	basesDir, patchesDir := path.Join(destDir, "crds/bases"), path.Join(destDir, "crds/patches")
+	if _, err := os.Stat(destDir); err == nil {
+		return basesDir, patchesDir, nil
+	}
+
+	if err := os.MkdirAll(s.cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create crd bundle cache dir %s, err: %w", s.cacheDir, err)
+	}
+	// Pull into a scratch directory first and only rename it into destDir
+	// once oras.Copy succeeds, for the same reason httpCRDSource.Fetch does:
+	// otherwise a pull that fails partway (auth hiccup, network blip, crash)
+	// leaves an empty/partial destDir behind, and the cache-hit check above
+	// would silently treat that as a successful fetch on the next call.
+	tmpDir, err := os.MkdirTemp(s.cacheDir, karmadaVersion+"-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create crd bundle scratch dir under %s, err: %w", s.cacheDir, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store, err := file.New(tmpDir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create oci content store at %s, err: %w", tmpDir, err)
+	}
+	defer store.Close()
+
+	repoRef, ref := s.reference, karmadaVersion
+	if i := strings.LastIndex(s.reference, "@"); i >= 0 {
+		// A digest-pinned reference (repo@sha256:...) lets oras-go verify the
+		// pulled manifest against that exact content digest instead of
+		// trusting whatever the mutable karmadaVersion tag currently
+		// resolves to.
+		repoRef, ref = s.reference[:i], s.reference[i+1:]
+	}
+
+	repo, err := remote.NewRepository(repoRef)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve oci reference %s, err: %w", repoRef, err)
+	}
+
+	if _, err := oras.Copy(ctx, repo, ref, store, ref, oras.DefaultCopyOptions); err != nil {
+		return "", "", fmt.Errorf("failed to pull crd bundle %s@%s, err: %w", repoRef, ref, err)
+	}
+
+	if err := os.Rename(tmpDir, destDir); err != nil {
+		return "", "", fmt.Errorf("failed to publish crd bundle to %s, err: %w", destDir, err)
+	}
+
+	klog.V(2).InfoS("[crdSource] Fetched karmada crd bundle from OCI registry", "reference", repoRef, "ref", ref, "dest", destDir)
+	return basesDir, patchesDir, nil
+}
+
+// sanitizeTarTarget resolves a tar entry's name against destDir and rejects
+// it if the resolved path escapes destDir, guarding against a malicious
+// tarball using ".." segments or an absolute path to write outside the
+// intended directory (CWE-22, "zip slip").
+func sanitizeTarTarget(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	destDirWithSep := filepath.Clean(destDir) + string(os.PathSeparator)
+	if target != filepath.Clean(destDir) && !strings.HasPrefix(target, destDirWithSep) {
+		return "", fmt.Errorf("tar entry %q escapes extraction directory %s", name, destDir)
+	}
+	return target, nil
+}
+
+// extractTarGz extracts a gzip-compressed tarball from r into destDir.
+func extractTarGz(r io.Reader, destDir string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream, err: %w", err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := sanitizeTarTarget(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+}
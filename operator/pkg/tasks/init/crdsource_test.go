@@ -0,0 +1,194 @@
+package tasks
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalCRDSourceFetch(t *testing.T) {
+	source := &localCRDSource{dataDir: "/data"}
+
+	basesDir, patchesDir, err := source.Fetch(context.Background(), "v1.9.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if basesDir != "/data/v1.9.0/crds/bases" {
+		t.Fatalf("unexpected basesDir: %q", basesDir)
+	}
+	if patchesDir != "/data/v1.9.0/crds/patches" {
+		t.Fatalf("unexpected patchesDir: %q", patchesDir)
+	}
+}
+
+func TestOCICRDSourceFailedPullDoesNotLeaveCacheHit(t *testing.T) {
+	cacheDir := t.TempDir()
+	// Port 1 is reserved and nothing listens on it, so oras.Copy fails fast
+	// on the dial instead of needing a real OCI registry to reject a pull.
+	source := &ociCRDSource{reference: "127.0.0.1:1/karmada/crds", cacheDir: cacheDir}
+
+	if _, _, err := source.Fetch(context.Background(), "v1.9.0"); err == nil {
+		t.Fatal("expected a failed oci pull to return an error")
+	}
+
+	if _, statErr := os.Stat(filepath.Join(cacheDir, "v1.9.0")); !os.IsNotExist(statErr) {
+		t.Fatal("a failed oci pull must not leave a cached destDir behind for the next Fetch to treat as a cache hit")
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("failed to read cache dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the scratch dir to be cleaned up on a failed pull, found %v", entries)
+	}
+}
+
+func TestOCICRDSourceUsesDigestPinnedReferenceWhenConfigured(t *testing.T) {
+	cacheDir := t.TempDir()
+	source := &ociCRDSource{reference: "127.0.0.1:1/karmada/crds@sha256:" + strings256Zeroes, cacheDir: cacheDir}
+
+	// The registry is unreachable, so Fetch fails at the pull; its error names
+	// the repository and the ref it tried to pull, which must be the pinned
+	// digest rather than the karmadaVersion tag.
+	_, _, err := source.Fetch(context.Background(), "v1.9.0")
+	if err == nil {
+		t.Fatal("expected a failed oci pull to return an error")
+	}
+	if want := "failed to pull crd bundle 127.0.0.1:1/karmada/crds@sha256:" + strings256Zeroes + ","; !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected Fetch to pull the pinned digest, want error containing %q, got: %v", want, err)
+	}
+}
+
+const strings256Zeroes = "0000000000000000000000000000000000000000000000000000000000000000"
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("failed to write tar header for %s: %v", name, err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write tar content for %s: %v", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTarGzRejectsPathTraversal(t *testing.T) {
+	destDir := t.TempDir()
+	archive := buildTarGz(t, map[string]string{
+		"../../etc/passwd": "pwned",
+	})
+
+	err := extractTarGz(bytes.NewReader(archive), destDir)
+	if err == nil {
+		t.Fatal("expected extractTarGz to reject a tar entry escaping destDir, got nil error")
+	}
+
+	if _, statErr := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(destDir)), "etc/passwd")); !os.IsNotExist(statErr) {
+		t.Fatal("tar entry escaped destDir and was written to disk")
+	}
+}
+
+func TestExtractTarGzWritesWellFormedArchive(t *testing.T) {
+	destDir := t.TempDir()
+	archive := buildTarGz(t, map[string]string{
+		"crds/bases/foo.yaml": "kind: CustomResourceDefinition",
+	})
+
+	if err := extractTarGz(bytes.NewReader(archive), destDir); err != nil {
+		t.Fatalf("unexpected error extracting a well-formed archive: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "crds/bases/foo.yaml"))
+	if err != nil {
+		t.Fatalf("expected extracted file to exist: %v", err)
+	}
+	if string(got) != "kind: CustomResourceDefinition" {
+		t.Fatalf("unexpected extracted content: %q", got)
+	}
+}
+
+func TestHTTPCRDSourceRejectsChecksumMismatchAndDoesNotCacheIt(t *testing.T) {
+	archive := buildTarGz(t, map[string]string{"crds/bases/foo.yaml": "bad"})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	source := &httpCRDSource{url: server.URL, checksum: "deadbeef", cacheDir: cacheDir}
+
+	_, _, err := source.Fetch(context.Background(), "v1.9.0")
+	if err == nil {
+		t.Fatal("expected a checksum mismatch error, got nil")
+	}
+
+	if _, statErr := os.Stat(filepath.Join(cacheDir, "v1.9.0")); !os.IsNotExist(statErr) {
+		t.Fatal("a checksum mismatch must not leave a cached destDir behind")
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("failed to read cache dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the scratch dir to be cleaned up on checksum mismatch, found %v", entries)
+	}
+}
+
+func TestHTTPCRDSourceFetchesAndCachesOnSuccess(t *testing.T) {
+	archive := buildTarGz(t, map[string]string{"crds/bases/foo.yaml": "ok"})
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	source := &httpCRDSource{url: server.URL, checksum: checksum, cacheDir: cacheDir}
+
+	basesDir, _, err := source.Fetch(context.Background(), "v1.9.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(basesDir, "foo.yaml"))
+	if err != nil {
+		t.Fatalf("expected bundle to be extracted under destDir: %v", err)
+	}
+	if string(got) != "ok" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	// A second Fetch must short-circuit on the now-verified destDir rather
+	// than re-downloading.
+	if _, _, err := source.Fetch(context.Background(), "v1.9.0"); err != nil {
+		t.Fatalf("unexpected error on cached fetch: %v", err)
+	}
+}
@@ -1,19 +1,27 @@
 package tasks
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path"
-	"regexp"
 	"strings"
+	"sync"
+	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	crdsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 
 	"github.com/karmada-io/karmada/operator/pkg/constants"
 	"github.com/karmada-io/karmada/operator/pkg/karmadaresource/apiservice"
@@ -23,6 +31,32 @@ import (
 	"github.com/karmada-io/karmada/operator/pkg/workflow"
 )
 
+// crdConversionWebhookCertName is the name given to the cert-manager
+// Certificate backing the CRD conversion webhook serving cert, when
+// cert-manager mode is enabled.
+const crdConversionWebhookCertName = "karmada-conversion-webhook-cert"
+
+// crdPatchTemplateData is the set of values exposed to CRD patch YAMLs when
+// they're rendered as Go templates.
+type crdPatchTemplateData struct {
+	Namespace      string
+	ServiceName    string
+	KarmadaVersion string
+	// CABundle is the base64-encoded CA bundle to inject into the conversion
+	// webhook's clientConfig. Empty when CertManagerEnabled is true, since
+	// cert-manager injects it via annotation instead.
+	CABundle string
+	// CertManagerEnabled indicates the conversion webhook CA is managed by
+	// cert-manager rather than by the operator.
+	CertManagerEnabled bool
+	// CertManagerAnnotations are merged onto the CRD's metadata.annotations
+	// when CertManagerEnabled is true.
+	CertManagerAnnotations map[string]string
+	// ConversionWebhookPath is the HTTP path the webhook server dispatches
+	// this CRD's conversion requests to. Set per-CRD by patchCrds.
+	ConversionWebhookPath string
+}
+
 // NewKarmadaResourcesTask init KarmadaResources task
 func NewKarmadaResourcesTask() workflow.Task {
 	return workflow.Task{
@@ -38,6 +72,10 @@ func NewKarmadaResourcesTask() workflow.Task {
 				Name: "crds",
 				Run:  runCrds,
 			},
+			{
+				Name: "crdsHealthCheck",
+				Run:  runCrdsHealthCheck,
+			},
 			{
 				Name: "WebhookConfiguration",
 				Run:  runWebhookConfiguration,
@@ -63,7 +101,7 @@ func runKarmadaResources(r workflow.RunData) error {
 func runSystemNamespace(r workflow.RunData) error {
 	data, ok := r.(InitData)
 	if !ok {
-		return errors.New("systemName task invoked with an invalid data struct")
+		return workflow.NewTaskError("systemNamespace", workflow.ReasonInvalidData, "systemNamespace task invoked with an invalid data struct", nil)
 	}
 
 	err := apiclient.CreateNamespace(data.KarmadaClient(), &corev1.Namespace{
@@ -72,7 +110,8 @@ func runSystemNamespace(r workflow.RunData) error {
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create namespace %s, err: %w", data.GetNamespace(), err)
+		return workflow.NewRetryableTaskError("systemNamespace", workflow.ReasonUnreachable,
+			fmt.Sprintf("failed to create namespace %s", data.GetNamespace()), err)
 	}
 
 	klog.V(2).InfoS("[systemName] Successfully created karmada system namespace", "namespace", data.GetNamespace(), "karmada", klog.KObj(data))
@@ -82,44 +121,117 @@ func runSystemNamespace(r workflow.RunData) error {
 func runCrds(r workflow.RunData) error {
 	data, ok := r.(InitData)
 	if !ok {
-		return errors.New("crds task invoked with an invalid data struct")
+		return workflow.NewTaskError("crds", workflow.ReasonInvalidData, "crds task invoked with an invalid data struct", nil)
 	}
 
-	var (
-		crdsDir       = path.Join(data.DataDir(), data.KarmadaVersion())
-		crdsPath      = path.Join(crdsDir, "crds/bases")
-		crdsPatchPath = path.Join(crdsDir, "crds/patches")
-	)
+	crdSource, err := resolveCRDSource(data)
+	if err != nil {
+		return workflow.NewTaskError("crds", workflow.ReasonInvalidConfig, "failed to resolve karmada crds source", err)
+	}
+
+	crdsPath, crdsPatchPath, err := crdSource.Fetch(context.TODO(), data.KarmadaVersion())
+	if err != nil {
+		return workflow.NewRetryableTaskError("crds", workflow.ReasonMissingDependency, "failed to fetch karmada crds", err)
+	}
 
 	crdsClient, err := apiclient.NewCRDsClient(data.ControlplaneConfig())
 	if err != nil {
-		return err
+		return workflow.NewRetryableTaskError("crds", workflow.ReasonUnreachable, "failed to build karmada crds client", err)
 	}
 
-	if err := createCrds(crdsClient, crdsPath); err != nil {
-		return fmt.Errorf("failed to create karmada crds, err: %w", err)
+	dynamicClient, err := dynamic.NewForConfig(data.ControlplaneConfig())
+	if err != nil {
+		return workflow.NewRetryableTaskError("crds", workflow.ReasonUnreachable, "failed to build dynamic client for karmada crds", err)
 	}
 
-	cert := data.GetCert(constants.CaCertAndKeyName)
-	if len(cert.CertData()) == 0 {
-		return errors.New("unexpect empty ca cert data")
+	concurrency := crdApplyConcurrency(data)
+	reporter := progressReporterFor(data)
+
+	if err := createCrds(crdsClient, dynamicClient, data.KarmadaClient(), data.GetNamespace(), data.KarmadaVersion(), crdsPath, shouldPruneOrphanCrds(data), concurrency, reporter); err != nil {
+		if apierrors.IsInvalid(err) {
+			// The apiserver rejected the server-side apply outright, which
+			// Force doesn't help with: an existing CRD's immutable field
+			// (e.g. spec.scope, spec.group) no longer matches what this
+			// karmada version ships, so re-running the task won't converge
+			// until the conflicting CRD is deleted and recreated.
+			return workflow.NewTaskError("crds", workflow.ReasonDrift,
+				"an existing crd no longer matches what the current karmada version expects and can't be reconciled via server-side apply; delete the conflicting crd so it can be recreated", err)
+		}
+		return workflow.NewRetryableTaskError("crds", workflow.ReasonUnreachable, "failed to create karmada crds", err)
 	}
 
-	caBase64 := base64.StdEncoding.EncodeToString(cert.CertData())
-	if err := patchCrds(crdsClient, crdsPatchPath, caBase64); err != nil {
-		return fmt.Errorf("failed to patch karmada crds, err: %w", err)
+	templateData := crdPatchTemplateData{
+		Namespace:      data.GetNamespace(),
+		ServiceName:    fmt.Sprintf("%s-webhook", data.GetName()),
+		KarmadaVersion: data.KarmadaVersion(),
+	}
+
+	if isCertManagerEnabled(data) {
+		certManagerVersion, err := detectCertManagerAPIVersion(crdsClient)
+		if err != nil {
+			return workflow.NewRetryableTaskError("crds", workflow.ReasonMissingDependency, "failed to detect cert-manager API version", err)
+		}
+
+		issuerName, issuerKind := certManagerIssuerRef(data)
+		if issuerName == "" {
+			return workflow.NewTaskError("crds", workflow.ReasonInvalidConfig,
+				"cert-manager mode is enabled but spec.certManager.issuerRef.name is empty; set it to an existing Issuer or ClusterIssuer", nil)
+		}
+
+		if err := ensureConversionCertificate(data.ControlplaneConfig(), certManagerVersion, data.GetNamespace(),
+			crdConversionWebhookCertName, crdConversionWebhookCertName, templateData.ServiceName, issuerName, issuerKind); err != nil {
+			return workflow.NewRetryableTaskError("crds", workflow.ReasonUnreachable, "failed to ensure cert-manager Certificate for crd conversion webhook", err)
+		}
+
+		templateData.CertManagerEnabled = true
+		templateData.CertManagerAnnotations = certManagerInjectCAAnnotation(data.GetNamespace(), crdConversionWebhookCertName)
+	} else {
+		cert := data.GetCert(constants.CaCertAndKeyName)
+		if len(cert.CertData()) == 0 {
+			return workflow.NewTaskError("crds", workflow.ReasonInvalidConfig, "unexpect empty ca cert data", nil)
+		}
+		templateData.CABundle = base64.StdEncoding.EncodeToString(cert.CertData())
+	}
+
+	if err := patchCrds(crdsClient, crdsPatchPath, templateData, concurrency, reporter); err != nil {
+		return workflow.NewRetryableTaskError("crds", workflow.ReasonUnreachable, "failed to patch karmada crds", err)
 	}
 
 	klog.V(2).InfoS("[systemName] Successfully applied karmada crds resource", "karmada", klog.KObj(data))
 	return nil
 }
 
-func createCrds(crdsClient *crdsclient.Clientset, crdsPath string) error {
-	for _, file := range util.ListFiles(crdsPath) {
-		if file.IsDir() || path.Ext(file.Name()) != ".yaml" {
-			continue
-		}
+// crdPruneOrphansAwareData is implemented by InitData when the Karmada CR
+// opts into pruning CRDs the current karmada version no longer ships
+// (spec.crds.pruneOrphans). Checked via a type assertion, defaulting to no
+// pruning, since leaving a CRD behind is the safer failure mode.
+type crdPruneOrphansAwareData interface {
+	PruneOrphanCRDs() bool
+}
+
+func shouldPruneOrphanCrds(data InitData) bool {
+	aware, ok := data.(crdPruneOrphansAwareData)
+	return ok && aware.PruneOrphanCRDs()
+}
+
+// createCrds server-side applies every CRD manifest under crdsPath, fanning
+// the work out across concurrency workers since each CRD is its own
+// round-trip to the API server. Re-running it on an upgrade reconciles
+// schema changes instead of skipping CRDs that already exist. It also
+// records the applied set in the per-namespace crd inventory ConfigMap and,
+// when pruneOrphans is set, removes CRDs the current karmadaVersion no
+// longer ships and that have no custom resources left.
+func createCrds(crdsClient *crdsclient.Clientset, dynamicClient dynamic.Interface, kubeClient kubernetes.Interface, namespace, karmadaVersion, crdsPath string, pruneOrphans bool, concurrency int, reporter workflow.ProgressReporter) error {
+	files := filterSlice(util.ListFiles(crdsPath), func(file os.FileInfo) bool {
+		return !file.IsDir() && path.Ext(file.Name()) == ".yaml"
+	})
+
+	var (
+		mu      sync.Mutex
+		desired = map[string]*apiextensionsv1.CustomResourceDefinition{}
+	)
 
+	err := forEachConcurrent(files, concurrency, "crds", reporter, func(file os.FileInfo) error {
 		crdBytes, err := util.ReadYamlFile(path.Join(crdsPath, file.Name()))
 		if err != nil {
 			return err
@@ -130,78 +242,221 @@ func createCrds(crdsClient *crdsclient.Clientset, crdsPath string) error {
 			klog.ErrorS(err, "error when converting json byte to apiExtensionsV1 CustomResourceDefinition struct")
 			return err
 		}
-		if err := apiclient.CreateCustomResourceDefinitionIfNeed(crdsClient, &obj); err != nil {
+		if err := apiclient.ApplyCustomResourceDefinition(crdsClient, &obj); err != nil {
 			return err
 		}
+
+		mu.Lock()
+		desired[obj.Name] = &obj
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+
+	snapshot, err := getCrdInventory(kubeClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	if pruneOrphans {
+		if err := pruneOrphanedCrds(crdsClient, dynamicClient, snapshot.inventory, karmadaVersion, desired); err != nil {
+			return err
+		}
+	}
+
+	desiredNames := make([]string, 0, len(desired))
+	for name := range desired {
+		desiredNames = append(desiredNames, name)
+	}
+	return saveCrdInventory(kubeClient, namespace, karmadaVersion, desiredNames, snapshot)
 }
 
-func patchCrds(crdsClient *crdsclient.Clientset, patchPath string, caBundle string) error {
-	for _, file := range util.ListFiles(patchPath) {
-		if file.IsDir() || path.Ext(file.Name()) != ".yaml" {
+// pruneOrphanedCrds deletes CRDs recorded against a different karmada
+// version than karmadaVersion that desired no longer lists, skipping any CRD
+// that still has custom resources so in-use data is never deleted silently.
+func pruneOrphanedCrds(crdsClient *crdsclient.Clientset, dynamicClient dynamic.Interface, inventory crdInventory, karmadaVersion string, desired map[string]*apiextensionsv1.CustomResourceDefinition) error {
+	orphans := map[string]struct{}{}
+	for version, names := range inventory {
+		if version == karmadaVersion {
 			continue
 		}
+		for _, name := range names {
+			if _, ok := desired[name]; !ok {
+				orphans[name] = struct{}{}
+			}
+		}
+	}
 
-		reg, err := regexp.Compile("{{caBundle}}")
+	for name := range orphans {
+		crd, err := crdsClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), name, metav1.GetOptions{})
 		if err != nil {
-			return err
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get orphaned crd %s, err: %w", name, err)
 		}
 
-		crdPath := path.Join(patchPath, file.Name())
-		crdBytes, err := util.RelpaceYamlForReg(crdPath, caBundle, reg)
+		inUse, err := apiclient.HasCustomResources(dynamicClient, crd)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to check for custom resources of orphaned crd %s, err: %w", name, err)
+		}
+		if inUse {
+			klog.InfoS("[crds] Refusing to prune orphaned crd, custom resources still exist", "crd", name)
+			continue
+		}
+
+		if err := crdsClient.ApiextensionsV1().CustomResourceDefinitions().Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to prune orphaned crd %s, err: %w", name, err)
 		}
+		klog.InfoS("[crds] Pruned orphaned crd no longer shipped by the current karmada version", "crd", name)
+	}
+	return nil
+}
 
+// patchCrds fans its work out across concurrency workers for the same
+// reason createCrds does: each CRD patch is its own round-trip to the API
+// server.
+func patchCrds(crdsClient *crdsclient.Clientset, patchPath string, templateData crdPatchTemplateData, concurrency int, reporter workflow.ProgressReporter) error {
+	files := filterSlice(util.ListFiles(patchPath), func(file os.FileInfo) bool {
+		return !file.IsDir() && path.Ext(file.Name()) == ".yaml"
+	})
+
+	return forEachConcurrent(files, concurrency, "crds-patch", reporter, func(file os.FileInfo) error {
 		crdResource := splitToCrdNameFormFile(file.Name(), "_", ".")
 		name := crdResource + ".work.karmada.io"
-		if err := apiclient.PatchCustomResourceDefinition(crdsClient, name, crdBytes); err != nil {
+
+		perCRDData := templateData
+		perCRDData.ConversionWebhookPath = fmt.Sprintf("/convert/%s", crdResource)
+
+		crdPath := path.Join(patchPath, file.Name())
+		crdBytes, err := renderCrdPatch(crdPath, perCRDData)
+		if err != nil {
+			return fmt.Errorf("failed to render crd patch %s, err: %w", file.Name(), err)
+		}
+
+		patchJSON, err := yaml.YAMLToJSON(crdBytes)
+		if err != nil {
+			return fmt.Errorf("failed to convert rendered crd patch %s to json, err: %w", file.Name(), err)
+		}
+		if err := apiclient.PatchCustomResourceDefinition(crdsClient, name, patchJSON); err != nil {
 			return err
 		}
+
+		if len(perCRDData.CertManagerAnnotations) == 0 {
+			return nil
+		}
+		annotationPatch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": perCRDData.CertManagerAnnotations},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode cert-manager annotations for crd %s, err: %w", name, err)
+		}
+		return apiclient.PatchCustomResourceDefinition(crdsClient, name, annotationPatch)
+	})
+}
+
+// renderCrdPatch renders the patch YAML at patchPath as a Go template,
+// exposing templateData and a sprig-style helper set to the template body,
+// and returns the rendered YAML.
+func renderCrdPatch(patchPath string, templateData crdPatchTemplateData) ([]byte, error) {
+	rawTemplate, err := os.ReadFile(patchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(path.Base(patchPath)).Funcs(templateFuncMap()).Parse(string(rawTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse crd patch template, err: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, templateData); err != nil {
+		return nil, fmt.Errorf("failed to execute crd patch template, err: %w", err)
+	}
+	return rendered.Bytes(), nil
+}
+
+func runCrdsHealthCheck(r workflow.RunData) error {
+	data, ok := r.(InitData)
+	if !ok {
+		return workflow.NewTaskError("crdsHealthCheck", workflow.ReasonInvalidData, "crdsHealthCheck task invoked with an invalid data struct", nil)
+	}
+
+	crdsClient, err := apiclient.NewCRDsClient(data.ControlplaneConfig())
+	if err != nil {
+		return workflow.NewRetryableTaskError("crdsHealthCheck", workflow.ReasonUnreachable, "failed to build karmada crds client", err)
+	}
+
+	var currentCACert []byte
+	if !isCertManagerEnabled(data) {
+		currentCACert = data.GetCert(constants.CaCertAndKeyName).CertData()
+	}
+
+	diagnostics, err := apiclient.AnalyzeCRDConversionWebhooks(crdsClient, data.KarmadaClient(), currentCACert)
+	if err != nil {
+		return workflow.NewRetryableTaskError("crdsHealthCheck", workflow.ReasonUnreachable, "failed to analyze karmada crd conversion webhooks", err)
+	}
+	if len(diagnostics) > 0 {
+		messages := make([]string, 0, len(diagnostics))
+		for _, d := range diagnostics {
+			messages = append(messages, d.String())
+		}
+		// ReasonUnhealthy is retryable everywhere it's used (see runAPIService):
+		// most diagnostics here, like a Service that hasn't propagated yet or a
+		// cert-manager-issued CA still rotating in, are transient right after
+		// install and clear up on their own.
+		return workflow.NewRetryableTaskError("crdsHealthCheck", workflow.ReasonUnhealthy,
+			fmt.Sprintf("found %d unhealthy crd conversion webhook(s):\n%s", len(diagnostics), strings.Join(messages, "\n")), nil)
 	}
+
+	klog.V(2).InfoS("[crdsHealthCheck] All karmada crd conversion webhooks are healthy", "karmada", klog.KObj(data))
 	return nil
 }
 
 func runWebhookConfiguration(r workflow.RunData) error {
 	data, ok := r.(InitData)
 	if !ok {
-		return errors.New("[webhookConfiguration] task invoked with an invalid data struct")
+		return workflow.NewTaskError("webhookConfiguration", workflow.ReasonInvalidData, "webhookConfiguration task invoked with an invalid data struct", nil)
 	}
 
 	cert := data.GetCert(constants.CaCertAndKeyName)
 	if len(cert.CertData()) == 0 {
-		return errors.New("unexpect empty ca cert data for webhookConfiguration")
+		return workflow.NewTaskError("webhookConfiguration", workflow.ReasonInvalidConfig, "unexpect empty ca cert data for webhookConfiguration", nil)
 	}
 
 	caBase64 := base64.StdEncoding.EncodeToString(cert.CertData())
-	return webhookconfiguration.EnsureWebhookConfiguration(
+	if err := webhookconfiguration.EnsureWebhookConfiguration(
 		data.KarmadaClient(),
 		data.GetNamespace(),
 		data.GetName(),
-		caBase64)
+		caBase64); err != nil {
+		return workflow.NewRetryableTaskError("webhookConfiguration", workflow.ReasonUnreachable, "failed to ensure karmada webhook configuration", err)
+	}
+	return nil
 }
 
 func runAPIService(r workflow.RunData) error {
 	data, ok := r.(InitData)
 	if !ok {
-		return errors.New("webhookConfiguration task invoked with an invalid data struct")
+		return workflow.NewTaskError("APIService", workflow.ReasonInvalidData, "APIService task invoked with an invalid data struct", nil)
 	}
 
 	config := data.ControlplaneConfig()
 	client, err := apiclient.NewAPIRegistrationClient(config)
 	if err != nil {
-		return err
+		return workflow.NewRetryableTaskError("APIService", workflow.ReasonUnreachable, "failed to build karmada api registration client", err)
 	}
 
 	err = apiservice.EnsureAggregatedAPIService(client, data.KarmadaClient(), data.GetName(), data.GetNamespace())
 	if err != nil {
-		return fmt.Errorf("failed to apply aggregated APIService resource to karmada controlplane, err: %w", err)
+		return workflow.NewRetryableTaskError("APIService", workflow.ReasonUnreachable, "failed to apply aggregated APIService resource to karmada controlplane", err)
 	}
 
 	waiter := apiclient.NewKarmadaWaiter(config, nil, componentBeReadyTimeout)
 	if err := waiter.WaitForAPIService(constants.APIServiceName); err != nil {
-		return fmt.Errorf("the APIService is unhealthy, err: %w", err)
+		return workflow.NewRetryableTaskError("APIService", workflow.ReasonUnhealthy, "the APIService is unhealthy", err)
 	}
 
 	klog.V(2).InfoS("[APIService] Aggregated APIService status is ready ", "karmada", klog.KObj(data))
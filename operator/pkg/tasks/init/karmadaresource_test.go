@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"github.com/karmada-io/karmada/operator/pkg/util/apiclient"
+	"github.com/karmada-io/karmada/operator/pkg/workflow"
+)
+
+// TestCreateCrdsSurfacesInvalidApplyError asserts that when the apiserver
+// rejects a server-side apply as Invalid (e.g. an immutable field changed on
+// an existing CRD), createCrds returns an error apierrors.IsInvalid can still
+// recognize through forEachConcurrent's errors.Join, since runCrds relies on
+// that to tell drift apart from a transient ReasonUnreachable failure.
+func TestCreateCrdsSurfacesInvalidApplyError(t *testing.T) {
+	crdsDir := t.TempDir()
+	crdDoc := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.work.karmada.io
+spec:
+  group: work.karmada.io
+  names:
+    kind: Widget
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+	if err := os.WriteFile(path.Join(crdsDir, "widget.yaml"), []byte(crdDoc), 0644); err != nil {
+		t.Fatalf("failed to write crd fixture: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := metav1.Status{
+			TypeMeta: metav1.TypeMeta{Kind: "Status"},
+			Status:   metav1.StatusFailure,
+			Reason:   metav1.StatusReasonInvalid,
+			Code:     http.StatusUnprocessableEntity,
+			Message:  "CustomResourceDefinition.apiextensions.k8s.io \"widgets.work.karmada.io\" is invalid: spec.scope: Invalid value: \"Namespaced\": field is immutable",
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		out, _ := json.Marshal(status)
+		_, _ = w.Write(out)
+	}))
+	defer server.Close()
+
+	crdsClient, err := apiclient.NewCRDsClient(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to build crds client: %v", err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	kubeClient := kubefake.NewSimpleClientset()
+
+	err = createCrds(crdsClient, dynamicClient, kubeClient, "karmada-system", "v1.9.0", crdsDir, false, 1, workflow.NoopProgressReporter{})
+	if err == nil {
+		t.Fatal("expected createCrds to surface the apiserver's rejection")
+	}
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected apierrors.IsInvalid(err) to see through forEachConcurrent's errors.Join, got: %v", err)
+	}
+}
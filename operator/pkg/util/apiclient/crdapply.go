@@ -0,0 +1,73 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/pointer"
+)
+
+// CRDFieldManager is the field manager used when server-side applying
+// karmada CRDs, so the operator's applied fields can be diffed and updated
+// on later runs without clobbering fields owned by other actors.
+const CRDFieldManager = "karmada-operator"
+
+// ApplyCustomResourceDefinition server-side applies crd using
+// CRDFieldManager, creating it if it doesn't exist yet or reconciling schema
+// changes if it does. Unlike CreateCustomResourceDefinitionIfNeed, this
+// makes repeated runs of the crds task a proper upgrade path rather than a
+// one-shot installer.
+func ApplyCustomResourceDefinition(client *crdsclient.Clientset, crd *apiextensionsv1.CustomResourceDefinition) error {
+	crd.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+	crd.Kind = "CustomResourceDefinition"
+
+	data, err := json.Marshal(crd)
+	if err != nil {
+		return fmt.Errorf("failed to marshal crd %s for server-side apply, err: %w", crd.Name, err)
+	}
+
+	_, err = client.ApiextensionsV1().CustomResourceDefinitions().Patch(
+		context.TODO(), crd.Name, types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: CRDFieldManager, Force: pointer.Bool(true)},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to server-side apply crd %s, err: %w", crd.Name, err)
+	}
+	return nil
+}
+
+// HasCustomResources reports whether any custom resource of one of crd's
+// served versions still exists in the cluster, used to refuse pruning a CRD
+// that's still in use.
+func HasCustomResources(dynamicClient dynamic.Interface, crd *apiextensionsv1.CustomResourceDefinition) (bool, error) {
+	for _, version := range crd.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+
+		gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: version.Name, Resource: crd.Spec.Names.Plural}
+		resourceClient := dynamicClient.Resource(gvr)
+		var list *unstructured.UnstructuredList
+		var err error
+		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			list, err = resourceClient.Namespace(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{Limit: 1})
+		} else {
+			list, err = resourceClient.List(context.TODO(), metav1.ListOptions{Limit: 1})
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s, err: %w", gvr.String(), err)
+		}
+		if len(list.Items) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,107 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workCRDGroup is the API group every CRD validated by
+// AnalyzeCRDConversionWebhooks belongs to.
+const workCRDGroup = "work.karmada.io"
+
+// ConversionWebhookDiagnostic describes one problem found with a CRD's
+// conversion webhook configuration.
+type ConversionWebhookDiagnostic struct {
+	CRDName string
+	Reason  string
+	Message string
+}
+
+func (d ConversionWebhookDiagnostic) String() string {
+	return fmt.Sprintf("crd %s: %s: %s", d.CRDName, d.Reason, d.Message)
+}
+
+// AnalyzeCRDConversionWebhooks walks every installed work.karmada.io CRD and
+// validates its spec.conversion block: that the strategy is Webhook, that
+// clientConfig.service points at a resolvable Service, that caBundle decodes
+// to a valid PEM certificate matching currentCACert, and that at least one
+// conversion review version is supported. It returns one diagnostic per
+// problem found instead of failing on the first, so every misconfigured CRD
+// is reported in a single pass. A nil slice means everything looks healthy.
+func AnalyzeCRDConversionWebhooks(crdsClient *crdsclient.Clientset, kubeClient kubernetes.Interface, currentCACert []byte) ([]ConversionWebhookDiagnostic, error) {
+	crdList, err := crdsClient.ApiextensionsV1().CustomResourceDefinitions().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CustomResourceDefinitions, err: %w", err)
+	}
+
+	var diagnostics []ConversionWebhookDiagnostic
+	for i := range crdList.Items {
+		crd := &crdList.Items[i]
+		if crd.Spec.Group != workCRDGroup {
+			continue
+		}
+		diagnostics = append(diagnostics, analyzeCRDConversion(crd, kubeClient, currentCACert)...)
+	}
+	return diagnostics, nil
+}
+
+func analyzeCRDConversion(crd *apiextensionsv1.CustomResourceDefinition, kubeClient kubernetes.Interface, currentCACert []byte) []ConversionWebhookDiagnostic {
+	var diagnostics []ConversionWebhookDiagnostic
+	addDiag := func(reason, message string) {
+		diagnostics = append(diagnostics, ConversionWebhookDiagnostic{CRDName: crd.Name, Reason: reason, Message: message})
+	}
+
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter {
+		addDiag("StrategyNotWebhook", "spec.conversion.strategy is not Webhook")
+		return diagnostics
+	}
+
+	webhook := conversion.Webhook
+	if webhook == nil || webhook.ClientConfig == nil || webhook.ClientConfig.Service == nil {
+		addDiag("MissingServiceRef", "spec.conversion.webhook.clientConfig.service is not set")
+		return diagnostics
+	}
+
+	if len(webhook.ConversionReviewVersions) == 0 {
+		addDiag("NoReviewVersions", "no conversion review versions are supported")
+	}
+
+	svcRef := webhook.ClientConfig.Service
+	if _, err := kubeClient.CoreV1().Services(svcRef.Namespace).Get(context.TODO(), svcRef.Name, metav1.GetOptions{}); err != nil {
+		addDiag("ServiceNotFound", fmt.Sprintf("service %s/%s is not resolvable: %v", svcRef.Namespace, svcRef.Name, err))
+	}
+
+	caBundle := webhook.ClientConfig.CABundle
+	if len(caBundle) == 0 {
+		addDiag("MissingCABundle", "clientConfig.caBundle is empty")
+		return diagnostics
+	}
+
+	block, _ := pem.Decode(caBundle)
+	if block == nil {
+		addDiag("InvalidCABundle", "clientConfig.caBundle does not decode as PEM")
+		return diagnostics
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		addDiag("InvalidCABundle", fmt.Sprintf("clientConfig.caBundle does not parse as an X.509 certificate: %v", err))
+		return diagnostics
+	}
+
+	if len(currentCACert) > 0 {
+		currentBlock, _ := pem.Decode(currentCACert)
+		if currentBlock == nil || !bytes.Equal(block.Bytes, currentBlock.Bytes) {
+			addDiag("CAMismatch", "clientConfig.caBundle does not match the current CA certificate, the conversion webhook's CA has likely rotated or expired")
+		}
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,113 @@
+package apiclient
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// AnalyzeCRDConversionWebhooks itself takes a concrete *crdsclient.Clientset,
+// which the apiextensions-apiserver fake clientset isn't assignable to, so
+// these tests exercise analyzeCRDConversion, the per-CRD logic it delegates
+// to for every diagnostic.
+
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBeTCCAR+gAwIBAgIUOnvXBuLJnbilRxJeT9W3Eb6fQbIwCgYIKoZIzj0EAwIw
+EjEQMA4GA1UEAwwHdGVzdC1jYTAeFw0yNjA3MjYxODI1MDBaFw0zNjA3MjMxODI1
+MDBaMBIxEDAOBgNVBAMMB3Rlc3QtY2EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNC
+AARDGDLstmsXIM6MrrMx4iXnPINx7bHKw2F4wj8FOM8gSdrgHNJt1KX6W4T++0gl
+0H/xEptIEW7mZUeY2977zz0Oo1MwUTAdBgNVHQ4EFgQU4BKpd679Qg+NYOI5o+03
+wYG2P1gwHwYDVR0jBBgwFoAU4BKpd679Qg+NYOI5o+03wYG2P1gwDwYDVR0TAQH/
+BAUwAwEB/zAKBggqhkjOPQQDAgNIADBFAiEA6IXnv3fBVEvodLakv4YExSxpPpQt
+RBpAika+a6VZBQ0CIHybC/5w2Z/v6RTo76G1frckNw48ZqgiHwNNw2x59vlf
+-----END CERTIFICATE-----`
+
+func crdWithConversion(t *testing.T, conversion *apiextensionsv1.CustomResourceConversion) *apiextensionsv1.CustomResourceDefinition {
+	t.Helper()
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "configurations.work.karmada.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:      workCRDGroup,
+			Conversion: conversion,
+		},
+	}
+}
+
+func TestAnalyzeCRDConversionStrategyNotWebhook(t *testing.T) {
+	crd := crdWithConversion(t, nil)
+	diags := analyzeCRDConversion(crd, fake.NewSimpleClientset(), nil)
+	if len(diags) != 1 || diags[0].Reason != "StrategyNotWebhook" {
+		t.Fatalf("expected a single StrategyNotWebhook diagnostic, got %+v", diags)
+	}
+}
+
+func TestAnalyzeCRDConversionMissingServiceRef(t *testing.T) {
+	crd := crdWithConversion(t, &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook:  &apiextensionsv1.WebhookConversion{},
+	})
+	diags := analyzeCRDConversion(crd, fake.NewSimpleClientset(), nil)
+	if len(diags) != 1 || diags[0].Reason != "MissingServiceRef" {
+		t.Fatalf("expected a single MissingServiceRef diagnostic, got %+v", diags)
+	}
+}
+
+func TestAnalyzeCRDConversionServiceNotFound(t *testing.T) {
+	crd := crdWithConversion(t, &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service:  &apiextensionsv1.ServiceReference{Namespace: "karmada-system", Name: "karmada-webhook"},
+				CABundle: []byte("not-pem"),
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	})
+
+	diags := analyzeCRDConversion(crd, fake.NewSimpleClientset(), nil)
+
+	var reasons []string
+	for _, d := range diags {
+		reasons = append(reasons, d.Reason)
+	}
+	if !containsReason(reasons, "ServiceNotFound") {
+		t.Fatalf("expected a ServiceNotFound diagnostic, got %+v", diags)
+	}
+	if !containsReason(reasons, "InvalidCABundle") {
+		t.Fatalf("expected an InvalidCABundle diagnostic for a non-PEM caBundle, got %+v", diags)
+	}
+}
+
+func TestAnalyzeCRDConversionHealthy(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "karmada-system", Name: "karmada-webhook"},
+	})
+
+	crd := crdWithConversion(t, &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service:  &apiextensionsv1.ServiceReference{Namespace: "karmada-system", Name: "karmada-webhook"},
+				CABundle: []byte(testCACert),
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	})
+
+	diags := analyzeCRDConversion(crd, kubeClient, nil)
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics for a healthy conversion webhook, got %+v", diags)
+	}
+}
+
+func containsReason(reasons []string, want string) bool {
+	for _, r := range reasons {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TaskErrorReason is a short, stable identifier for why a Task failed,
+// suitable for keying retry/backoff decisions in the workflow runner or for
+// surfacing on a Karmada CR's status.reason field.
+type TaskErrorReason string
+
+const (
+	// ReasonInvalidData means Task.Run was invoked with a RunData that
+	// doesn't satisfy the interface the task expects.
+	ReasonInvalidData TaskErrorReason = "InvalidData"
+	// ReasonInvalidConfig means the Karmada CR or operator configuration is
+	// invalid or incomplete; re-running the task won't help until it's fixed.
+	ReasonInvalidConfig TaskErrorReason = "InvalidConfig"
+	// ReasonDrift means a resource already exists but no longer matches what
+	// the current karmada version expects.
+	ReasonDrift TaskErrorReason = "Drift"
+	// ReasonUnreachable means a call to the karmada control plane or host
+	// cluster API server failed, typically transient.
+	ReasonUnreachable TaskErrorReason = "Unreachable"
+	// ReasonMissingDependency means the task depends on something that isn't
+	// present yet, e.g. an optional CRD or controller.
+	ReasonMissingDependency TaskErrorReason = "MissingDependency"
+	// ReasonUnhealthy means a resource was applied successfully but isn't
+	// reporting healthy.
+	ReasonUnhealthy TaskErrorReason = "Unhealthy"
+)
+
+// TaskError is returned by Task.Run implementations in place of opaque
+// fmt.Errorf-wrapped strings, so the workflow runner can implement
+// per-reason retry/backoff and the Karmada CR status can surface a stable
+// reason field, rather than every caller having to pattern-match error text.
+type TaskError struct {
+	// Phase names the task that produced this error, e.g. "crds".
+	Phase string
+	// Reason is a short, stable identifier for what went wrong.
+	Reason TaskErrorReason
+	// Retryable indicates whether simply re-running the task might succeed,
+	// e.g. true for a transient API server outage and false for an invalid
+	// Karmada CR spec.
+	Retryable bool
+	// Message is a human-readable description of the failure.
+	Message string
+	// Cause is the underlying error, if any.
+	Cause error
+}
+
+func (e *TaskError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("[%s] %s: %s: %v", e.Phase, e.Reason, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("[%s] %s: %s", e.Phase, e.Reason, e.Message)
+}
+
+// Unwrap lets errors.Is/errors.As see through a TaskError to its Cause.
+func (e *TaskError) Unwrap() error {
+	return e.Cause
+}
+
+// NewTaskError builds a non-retryable TaskError.
+func NewTaskError(phase string, reason TaskErrorReason, message string, cause error) *TaskError {
+	return &TaskError{Phase: phase, Reason: reason, Message: message, Cause: cause}
+}
+
+// NewRetryableTaskError builds a TaskError the workflow runner may retry.
+func NewRetryableTaskError(phase string, reason TaskErrorReason, message string, cause error) *TaskError {
+	return &TaskError{Phase: phase, Reason: reason, Retryable: true, Message: message, Cause: cause}
+}
+
+// AsTaskError reports whether err is, or wraps, a *TaskError, mirroring the
+// errors.As convention used elsewhere for typed error inspection.
+func AsTaskError(err error) (*TaskError, bool) {
+	var taskErr *TaskError
+	ok := errors.As(err, &taskErr)
+	return taskErr, ok
+}
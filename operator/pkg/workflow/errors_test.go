@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTaskErrorRetryability(t *testing.T) {
+	nonRetryable := NewTaskError("crds", ReasonInvalidConfig, "bad config", nil)
+	if nonRetryable.Retryable {
+		t.Fatal("NewTaskError must build a non-retryable TaskError")
+	}
+
+	retryable := NewRetryableTaskError("crds", ReasonUnreachable, "apiserver unreachable", nil)
+	if !retryable.Retryable {
+		t.Fatal("NewRetryableTaskError must build a retryable TaskError")
+	}
+}
+
+func TestTaskErrorErrorString(t *testing.T) {
+	cause := errors.New("connection refused")
+	withCause := NewRetryableTaskError("crds", ReasonUnreachable, "failed to create karmada crds", cause)
+	if got := withCause.Error(); got != "[crds] Unreachable: failed to create karmada crds: connection refused" {
+		t.Fatalf("unexpected error string: %q", got)
+	}
+
+	withoutCause := NewTaskError("crds", ReasonInvalidConfig, "unexpect empty ca cert data", nil)
+	if got := withoutCause.Error(); got != "[crds] InvalidConfig: unexpect empty ca cert data" {
+		t.Fatalf("unexpected error string: %q", got)
+	}
+}
+
+func TestAsTaskError(t *testing.T) {
+	taskErr := NewRetryableTaskError("crds", ReasonUnreachable, "failed to create karmada crds", errors.New("boom"))
+	wrapped := fmt.Errorf("runCrds: %w", taskErr)
+
+	got, ok := AsTaskError(wrapped)
+	if !ok {
+		t.Fatal("expected AsTaskError to find the wrapped TaskError")
+	}
+	if got.Reason != ReasonUnreachable || !got.Retryable {
+		t.Fatalf("unexpected unwrapped TaskError: %+v", got)
+	}
+
+	if _, ok := AsTaskError(errors.New("plain error")); ok {
+		t.Fatal("expected AsTaskError to report false for a non-TaskError")
+	}
+}
@@ -0,0 +1,18 @@
+package workflow
+
+// ProgressReporter receives incremental progress updates from a Task as it
+// completes units of work, so a caller such as the operator controller can
+// publish a live %complete onto the Karmada CR status instead of only
+// learning about a task's outcome once it returns.
+type ProgressReporter interface {
+	// Report is called with the task name, the number of units completed so
+	// far, and the total number of units the task expects to process.
+	Report(task string, completed, total int)
+}
+
+// NoopProgressReporter discards every progress update. It's the reporter
+// tasks fall back to when their RunData doesn't provide one.
+type NoopProgressReporter struct{}
+
+// Report implements ProgressReporter.
+func (NoopProgressReporter) Report(string, int, int) {}